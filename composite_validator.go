@@ -0,0 +1,132 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ship
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// MultiError aggregates the errors produced by a composite Validator. It
+// implements Unwrap() []error so that errors.Is and errors.As can inspect
+// each one.
+type MultiError []error
+
+func (e MultiError) Error() string {
+	if len(e) == 0 {
+		return "no error"
+	}
+
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual errors, for errors.Is/errors.As.
+func (e MultiError) Unwrap() []error { return e }
+
+// NewChainValidator returns a Validator running vs in order, stopping and
+// returning the first error, including ctx.Err() once ctx is canceled
+// before the next one runs. Use it to run, say, a struct-tag Validator
+// before a slower business-rule Validator that shouldn't run on data
+// that already failed the cheap checks.
+func NewChainValidator(vs ...Validator) Validator {
+	return ValidatorFunc(func(ctx context.Context, data interface{}) error {
+		for _, v := range vs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := v.Validate(ctx, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// NewParallelValidator returns a Validator running vs concurrently and
+// aggregating every failure into a MultiError. If ctx is canceled before
+// all of vs finish, for example because the client disconnected, it
+// returns ctx.Err() immediately rather than waiting for the stragglers;
+// well-behaved validators are expected to watch ctx.Done() themselves and
+// return early.
+func NewParallelValidator(vs ...Validator) Validator {
+	return ValidatorFunc(func(ctx context.Context, data interface{}) error {
+		var (
+			mu   sync.Mutex
+			errs MultiError
+			wg   sync.WaitGroup
+		)
+
+		for _, v := range vs {
+			wg.Add(1)
+			go func(v Validator) {
+				defer wg.Done()
+				if err := v.Validate(ctx, data); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}(v)
+		}
+
+		done := make(chan struct{})
+		go func() { wg.Wait(); close(done) }()
+
+		select {
+		case <-done:
+			if len(errs) == 0 {
+				return nil
+			}
+			return errs
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// CrossFieldRule validates data as a whole, for a constraint spanning
+// multiple fields that a single struct tag can't express, such as
+// "StartDate must be before EndDate".
+type CrossFieldRule func(data interface{}) error
+
+// CrossFieldValidator is a Validator whose rules are registered one at a
+// time, and run in registration order, stopping at the first error.
+type CrossFieldValidator struct {
+	rules []CrossFieldRule
+}
+
+// NewCrossFieldValidator returns an empty CrossFieldValidator.
+func NewCrossFieldValidator() *CrossFieldValidator { return new(CrossFieldValidator) }
+
+// Register adds rule, returning the receiver so that calls can be
+// chained.
+func (v *CrossFieldValidator) Register(rule CrossFieldRule) *CrossFieldValidator {
+	v.rules = append(v.rules, rule)
+	return v
+}
+
+// Validate implements the Validator interface.
+func (v *CrossFieldValidator) Validate(_ context.Context, data interface{}) error {
+	for _, rule := range v.rules {
+		if err := rule(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,693 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/xmx/ship"
+)
+
+// EncoderWriter is implemented by a compressor writer that the Compress
+// middleware can reuse between requests via a sync.Pool.
+type EncoderWriter interface {
+	io.Writer
+
+	// Reset discards the writer's state, if any, and makes it equivalent
+	// to the result of its original NewWriter, but writing to w instead.
+	Reset(w io.Writer)
+
+	// Flush flushes any pending compressed data to the underlying writer.
+	Flush() error
+
+	// Close closes the writer, flushing any unwritten data, and releases
+	// it back to the pool it was acquired from.
+	Close() error
+}
+
+// Encoder represents a pluggable compression algorithm, such as gzip,
+// deflate, br or zstd, bound to a fixed compression level.
+type Encoder interface {
+	// Name returns the token used in the Accept-Encoding and
+	// Content-Encoding headers, such as "gzip".
+	Name() string
+
+	// NewWriter acquires an EncoderWriter writing the compressed data to
+	// w, reusing one from the encoder's own sync.Pool where possible.
+	NewWriter(w io.Writer) EncoderWriter
+}
+
+var (
+	encoderFactoriesLock sync.RWMutex
+	encoderFactories     = make(map[string]func(level int) Encoder, 8)
+)
+
+// RegisterEncoder registers a global Encoder factory under name, so that
+// CompressConfig.Levels can enable it by that name. "identity" is reserved
+// and must not be registered. It panics if name is empty, "identity" or
+// has already been registered.
+func RegisterEncoder(name string, factory func(level int) Encoder) {
+	if name == "" || name == "identity" {
+		panic(fmt.Errorf("compress: invalid encoder name '%s'", name))
+	}
+
+	encoderFactoriesLock.Lock()
+	defer encoderFactoriesLock.Unlock()
+	if _, ok := encoderFactories[name]; ok {
+		panic(fmt.Errorf("compress: encoder '%s' has been registered", name))
+	}
+	encoderFactories[name] = factory
+}
+
+func getEncoderFactory(name string) (factory func(level int) Encoder, ok bool) {
+	encoderFactoriesLock.RLock()
+	factory, ok = encoderFactories[name]
+	encoderFactoriesLock.RUnlock()
+	return
+}
+
+func init() {
+	RegisterEncoder("gzip", newGzipEncoder)
+	RegisterEncoder("deflate", newDeflateEncoder)
+	RegisterEncoder("br", newBrotliEncoder)
+	RegisterEncoder("zstd", newZstdEncoder)
+}
+
+// CompressConfig is used to configure the Compress middleware.
+type CompressConfig struct {
+	// Levels configures the compression level for each enabled encoding,
+	// keyed by its Accept-Encoding token, such as "gzip", "deflate", "br"
+	// or "zstd". An encoding is only selectable by the middleware if it
+	// has an entry here.
+	//
+	// Default: {"gzip": gzip.DefaultCompression}
+	Levels map[string]int
+
+	// MinLength is the minimum number of response body bytes that must
+	// be buffered before the middleware decides to compress. Responses
+	// shorter than MinLength are written through unmodified, since the
+	// overhead of compression would outweigh the benefit.
+	//
+	// Default: 1024
+	MinLength int
+
+	// ContentTypes restricts compression to the responses whose declared
+	// or sniffed Content-Type matches one of the given patterns, such as
+	// "text/*" or "application/json". If empty, all content types are
+	// eligible.
+	//
+	// Default: nil
+	ContentTypes []string
+
+	// Domains is the host domains enabling the compression. See Gzip
+	// for the supported exact, prefix and suffix match syntax.
+	//
+	// Default: nil
+	Domains []string
+}
+
+// Compress returns a middleware that negotiates a compression encoding
+// from the request's Accept-Encoding header, with q-value support, and
+// compresses the response with it.
+//
+// Unlike Gzip, Compress only compresses the response once MinLength bytes
+// have been buffered, matches the response Content-Type against
+// ContentTypes, and refuses to double-encode a response that already has
+// a Content-Encoding.
+//
+// Notice: like Gzip, the Compress middleware must be the last to handle
+// the response, that's, the error handler middleware must be appended
+// after it.
+func Compress(config *CompressConfig) Middleware {
+	var conf CompressConfig
+	if config != nil {
+		conf = *config
+	}
+	if conf.Levels == nil {
+		conf.Levels = map[string]int{"gzip": gzip.DefaultCompression}
+	}
+	if conf.MinLength <= 0 {
+		conf.MinLength = 1024
+	}
+
+	encoders := make(map[string]Encoder, len(conf.Levels))
+	for name, level := range conf.Levels {
+		factory, ok := getEncoderFactory(name)
+		if !ok {
+			panic(fmt.Errorf("compress: unregistered encoding '%s'", name))
+		}
+		encoders[name] = factory(level)
+	}
+
+	domains := newDomainMatcher(conf.Domains)
+	minLength := conf.MinLength
+	contentTypes := conf.ContentTypes
+	order := preferredEncoderOrder(encoders)
+
+	return func(next ship.Handler) ship.Handler {
+		return func(ctx *ship.Context) error {
+			if domains.Empty() || domains.Match(splitHost(ctx.Host())) {
+				if enc := negotiate(ctx.GetReqHeader(ship.HeaderAcceptEncoding), encoders, order); enc != nil {
+					cresp := &compressResponse{
+						ResponseWriter: ctx.ResponseWriter(),
+						ctx:            ctx,
+						enc:            enc,
+						minLength:      minLength,
+						contentTypes:   contentTypes,
+					}
+					defer cresp.finish()
+					ctx.SetResponse(cresp)
+				}
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+type acceptedEncoding struct {
+	name string
+	q    float64
+}
+
+// negotiate parses the Accept-Encoding header with its q-values and
+// returns the highest-priority Encoder available in encoders, or nil if
+// the client does not accept any of them (including an explicit
+// preference for "identity"). order breaks the tie for a wildcard
+// "Accept-Encoding: *" and must list every key of encoders.
+func negotiate(header string, encoders map[string]Encoder, order []string) Encoder {
+	for _, accepted := range parseAcceptEncoding(header) {
+		if accepted.q <= 0 {
+			continue
+		}
+
+		switch accepted.name {
+		case "identity":
+			return nil
+		case "*":
+			if len(order) > 0 {
+				return encoders[order[0]]
+			}
+			return nil
+		default:
+			if enc, ok := encoders[accepted.name]; ok {
+				return enc
+			}
+		}
+	}
+	return nil
+}
+
+// encoderPreference ranks the built-in encoders from the most to the
+// least generally desirable, so that a wildcard "Accept-Encoding: *"
+// picks the same one every time instead of a random map entry.
+var encoderPreference = []string{"zstd", "br", "gzip", "deflate"}
+
+// preferredEncoderOrder returns the keys of encoders sorted by
+// encoderPreference, with any encoder registered under a name outside of
+// that list appended afterwards in alphabetical order. The result is
+// deterministic for a given set of encoders, unlike ranging over the map.
+func preferredEncoderOrder(encoders map[string]Encoder) []string {
+	order := make([]string, 0, len(encoders))
+	seen := make(map[string]bool, len(encoders))
+
+	for _, name := range encoderPreference {
+		if _, ok := encoders[name]; ok {
+			order = append(order, name)
+			seen[name] = true
+		}
+	}
+
+	rest := make([]string, 0, len(encoders)-len(order))
+	for name := range encoders {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(order, rest...)
+}
+
+func parseAcceptEncoding(header string) []acceptedEncoding {
+	if header == "" {
+		return nil
+	}
+
+	parts := strings.Split(header, ",")
+	accepted := make([]acceptedEncoding, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, q := part, 1.0
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			name = strings.TrimSpace(part[:i])
+			if v, ok := parseQValue(part[i+1:]); ok {
+				q = v
+			}
+		}
+		accepted = append(accepted, acceptedEncoding{name: strings.ToLower(name), q: q})
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].q > accepted[j].q })
+	return accepted
+}
+
+func parseQValue(param string) (float64, bool) {
+	param = strings.TrimSpace(param)
+	if !strings.HasPrefix(param, "q=") {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(param[2:], 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// matchContentType reports whether contentType matches one of patterns,
+// which may be an exact media type such as "application/json" or end in
+// "/*" to match a whole type such as "text/*".
+func matchContentType(patterns []string, contentType string) bool {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.TrimSpace(contentType)
+
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/*") {
+			if strings.HasPrefix(contentType, pattern[:len(pattern)-1]) {
+				return true
+			}
+		} else if pattern == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// compressResponse wraps a http.ResponseWriter and buffers the first
+// minLength bytes of the response before deciding whether to run them
+// through enc or to pass them through unmodified.
+type compressResponse struct {
+	http.ResponseWriter
+
+	ctx          *ship.Context
+	enc          Encoder
+	minLength    int
+	contentTypes []string
+
+	buf                []byte
+	writer             EncoderWriter
+	decided            bool
+	compress           bool
+	precompressed      bool
+	precompressChecked bool
+
+	wroteHeader bool
+	statusCode  int
+}
+
+func (c *compressResponse) WriteHeader(code int) {
+	if c.decided {
+		c.ResponseWriter.WriteHeader(code)
+		return
+	}
+	c.wroteHeader = true
+	c.statusCode = code
+}
+
+func (c *compressResponse) Write(b []byte) (int, error) {
+	if c.decided {
+		return c.write(b)
+	}
+
+	if !c.precompressChecked {
+		c.precompressChecked = true
+		served, err := c.servePrecompressed()
+		if err != nil {
+			return 0, err
+		}
+		if served {
+			c.decided = true
+			c.precompressed = true
+			return len(b), nil
+		}
+	}
+
+	c.buf = append(c.buf, b...)
+	if len(c.buf) < c.minLength {
+		return len(b), nil
+	}
+	if err := c.decide(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *compressResponse) write(b []byte) (int, error) {
+	switch {
+	case c.precompressed:
+		return len(b), nil
+	case c.compress:
+		return c.writer.Write(b)
+	default:
+		return c.ResponseWriter.Write(b)
+	}
+}
+
+// servePrecompressed streams a prebuilt "<path>.gz" or "<path>.br"
+// sidecar for the file marked by ctx.SetPrecompressedCandidate, if one
+// exists for the negotiated encoding, preserving the original file's
+// ETag and Last-Modified and skipping runtime compression entirely.
+func (c *compressResponse) servePrecompressed() (bool, error) {
+	if c.noBody() {
+		return false, nil
+	}
+
+	path, ok := c.ctx.PrecompressedCandidate()
+	if !ok || c.Header().Get("Content-Encoding") != "" {
+		return false, nil
+	}
+
+	ext := sidecarExtension(c.enc.Name())
+	if ext == "" {
+		return false, nil
+	}
+
+	original, err := os.Stat(path)
+	if err != nil {
+		return false, nil
+	}
+
+	sidecar, err := os.Open(path + ext)
+	if err != nil {
+		return false, nil
+	}
+	defer sidecar.Close()
+
+	info, err := sidecar.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	h := c.Header()
+	h.Set("Content-Encoding", c.enc.Name())
+	h.Add("Vary", "Accept-Encoding")
+	h.Set("Last-Modified", original.ModTime().UTC().Format(http.TimeFormat))
+	h.Set("ETag", weakETag(original))
+	h.Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+
+	if c.wroteHeader {
+		c.ResponseWriter.WriteHeader(c.statusCode)
+	}
+	_, err = io.Copy(c.ResponseWriter, sidecar)
+	return true, err
+}
+
+// noBody reports whether the original, uncompressed response would carry
+// no entity body, in which case streaming the sidecar's body would be an
+// HTTP protocol violation: a HEAD request never gets one, and neither
+// does a 304 Not Modified or 204 No Content written by, say,
+// http.ServeContent reacting to a conditional GET.
+func (c *compressResponse) noBody() bool {
+	if c.ctx.Request().Method == http.MethodHead {
+		return true
+	}
+	if !c.wroteHeader {
+		return false
+	}
+
+	switch c.statusCode {
+	case http.StatusNoContent, http.StatusNotModified:
+		return true
+	default:
+		return c.statusCode >= 100 && c.statusCode < 200
+	}
+}
+
+func sidecarExtension(encoding string) string {
+	switch encoding {
+	case "gzip":
+		return ".gz"
+	case "br":
+		return ".br"
+	default:
+		return ""
+	}
+}
+
+func weakETag(info os.FileInfo) string {
+	return fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
+
+// decide chooses, based on any pre-existing Content-Encoding header and
+// the configured ContentTypes allowlist, whether the buffered bytes
+// should be compressed, then flushes them through the chosen path.
+func (c *compressResponse) decide() error {
+	buf := c.buf
+	c.buf = nil
+	c.decided = true
+	c.compress = c.Header().Get("Content-Encoding") == "" && c.eligible(buf)
+
+	if c.compress {
+		c.Header().Set("Content-Encoding", c.enc.Name())
+		c.Header().Add("Vary", "Accept-Encoding")
+		c.Header().Del("Content-Length")
+		c.writer = c.enc.NewWriter(c.ResponseWriter)
+	}
+
+	if c.wroteHeader {
+		c.ResponseWriter.WriteHeader(c.statusCode)
+	}
+
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := c.write(buf)
+	return err
+}
+
+func (c *compressResponse) eligible(buf []byte) bool {
+	if len(c.contentTypes) == 0 {
+		return true
+	}
+
+	ct := c.Header().Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(buf)
+	}
+	return matchContentType(c.contentTypes, ct)
+}
+
+// Flush forces a decision on any still-buffered bytes, then flushes the
+// compressor, if any, and the underlying ResponseWriter.
+func (c *compressResponse) Flush() {
+	if !c.decided {
+		if err := c.decide(); err != nil {
+			return
+		}
+	}
+	if c.compress {
+		c.writer.Flush()
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish flushes any bytes that never reached minLength, and closes the
+// compressor writer, releasing it back to its pool.
+func (c *compressResponse) finish() {
+	if !c.decided {
+		if !c.precompressChecked {
+			c.precompressChecked = true
+			if served, err := c.servePrecompressed(); err == nil && served {
+				c.decided = true
+				c.precompressed = true
+				return
+			}
+		}
+
+		c.decided = true
+		if c.wroteHeader {
+			c.ResponseWriter.WriteHeader(c.statusCode)
+		}
+		if len(c.buf) > 0 {
+			c.ResponseWriter.Write(c.buf)
+			c.buf = nil
+		}
+		return
+	}
+	if c.compress {
+		c.writer.Close()
+	}
+}
+
+// Unwrap allows http.ResponseController (Hijack, write deadlines, etc. on
+// the underlying writer) to see through the wrapper.
+func (c *compressResponse) Unwrap() http.ResponseWriter { return c.ResponseWriter }
+
+// Push implements http.Pusher by forwarding to the underlying writer, or
+// returns http.ErrNotSupported if it is not an http.Pusher.
+func (c *compressResponse) Push(target string, opts *http.PushOptions) error {
+	if p, ok := c.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+type gzipEncoder struct{ pool sync.Pool }
+
+func newGzipEncoder(level int) Encoder {
+	e := &gzipEncoder{}
+	e.pool.New = func() interface{} {
+		w, err := gzip.NewWriterLevel(io.Discard, level)
+		if err != nil {
+			panic(err)
+		}
+		return &gzipEncoderWriter{Writer: w, enc: e}
+	}
+	return e
+}
+
+func (e *gzipEncoder) Name() string { return "gzip" }
+
+func (e *gzipEncoder) NewWriter(w io.Writer) EncoderWriter {
+	ew := e.pool.Get().(*gzipEncoderWriter)
+	ew.Writer.Reset(w)
+	return ew
+}
+
+type gzipEncoderWriter struct {
+	*gzip.Writer
+	enc *gzipEncoder
+}
+
+func (w *gzipEncoderWriter) Close() error {
+	err := w.Writer.Close()
+	w.enc.pool.Put(w)
+	return err
+}
+
+type deflateEncoder struct{ pool sync.Pool }
+
+func newDeflateEncoder(level int) Encoder {
+	e := &deflateEncoder{}
+	e.pool.New = func() interface{} {
+		w, err := flate.NewWriter(io.Discard, level)
+		if err != nil {
+			panic(err)
+		}
+		return &deflateEncoderWriter{Writer: w, enc: e}
+	}
+	return e
+}
+
+func (e *deflateEncoder) Name() string { return "deflate" }
+
+func (e *deflateEncoder) NewWriter(w io.Writer) EncoderWriter {
+	ew := e.pool.Get().(*deflateEncoderWriter)
+	ew.Writer.Reset(w)
+	return ew
+}
+
+type deflateEncoderWriter struct {
+	*flate.Writer
+	enc *deflateEncoder
+}
+
+func (w *deflateEncoderWriter) Close() error {
+	err := w.Writer.Close()
+	w.enc.pool.Put(w)
+	return err
+}
+
+type brotliEncoder struct{ pool sync.Pool }
+
+func newBrotliEncoder(level int) Encoder {
+	e := &brotliEncoder{}
+	e.pool.New = func() interface{} {
+		w := brotli.NewWriterLevel(io.Discard, level)
+		return &brotliEncoderWriter{Writer: w, enc: e}
+	}
+	return e
+}
+
+func (e *brotliEncoder) Name() string { return "br" }
+
+func (e *brotliEncoder) NewWriter(w io.Writer) EncoderWriter {
+	ew := e.pool.Get().(*brotliEncoderWriter)
+	ew.Writer.Reset(w)
+	return ew
+}
+
+type brotliEncoderWriter struct {
+	*brotli.Writer
+	enc *brotliEncoder
+}
+
+func (w *brotliEncoderWriter) Close() error {
+	err := w.Writer.Close()
+	w.enc.pool.Put(w)
+	return err
+}
+
+type zstdEncoder struct{ pool sync.Pool }
+
+func newZstdEncoder(level int) Encoder {
+	e := &zstdEncoder{}
+	e.pool.New = func() interface{} {
+		w, err := zstd.NewWriter(io.Discard, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		if err != nil {
+			panic(err)
+		}
+		return &zstdEncoderWriter{Encoder: w, enc: e}
+	}
+	return e
+}
+
+func (e *zstdEncoder) Name() string { return "zstd" }
+
+func (e *zstdEncoder) NewWriter(w io.Writer) EncoderWriter {
+	ew := e.pool.Get().(*zstdEncoderWriter)
+	ew.Encoder.Reset(w)
+	return ew
+}
+
+type zstdEncoderWriter struct {
+	*zstd.Encoder
+	enc *zstdEncoder
+}
+
+func (w *zstdEncoderWriter) Close() error {
+	err := w.Encoder.Close()
+	w.enc.pool.Put(w)
+	return err
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import "strings"
+
+// domainMatcher matches a host against a set of domain patterns supporting
+// the exact, prefix and suffix match. For example,
+//
+//	Exact:  www.example.com
+//	Prefix: www.example.*
+//	Suffix: *.example.com
+type domainMatcher struct {
+	exact  []string
+	prefix []string
+	suffix []string
+}
+
+// newDomainMatcher builds a domainMatcher from the raw domain patterns.
+// It panics if one of the domains is empty.
+func newDomainMatcher(domains []string) domainMatcher {
+	var m domainMatcher
+	for _, domain := range domains {
+		switch {
+		case domain == "":
+			panic("middleware: empty domain")
+		case strings.HasPrefix(domain, "*."):
+			m.suffix = append(m.suffix, domain[1:])
+		case strings.HasSuffix(domain, ".*"):
+			m.prefix = append(m.prefix, domain[:len(domain)-1])
+		default:
+			m.exact = append(m.exact, domain)
+		}
+	}
+	return m
+}
+
+// Empty reports whether no domain pattern has been configured, in which
+// case the caller usually treats every host as matched.
+func (m domainMatcher) Empty() bool {
+	return len(m.exact) == 0 && len(m.prefix) == 0 && len(m.suffix) == 0
+}
+
+// Match reports whether host matches one of the configured patterns.
+func (m domainMatcher) Match(host string) bool {
+	for _, d := range m.exact {
+		if d == host {
+			return true
+		}
+	}
+	for _, d := range m.prefix {
+		if strings.HasPrefix(host, d) {
+			return true
+		}
+	}
+	for _, d := range m.suffix {
+		if strings.HasSuffix(host, d) {
+			return true
+		}
+	}
+	return false
+}
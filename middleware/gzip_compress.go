@@ -79,45 +79,12 @@ func Gzip(config *GZipConfig) Middleware {
 		return
 	}
 
-	var exactDomains []string
-	var prefixDomains []string
-	var suffixDomains []string
-	for _, domain := range conf.Domains {
-		if domain == "" {
-			panic("GZip: empty domain")
-		} else if strings.HasPrefix(domain, "*.") {
-			suffixDomains = append(suffixDomains, domain[1:])
-		} else if strings.HasSuffix(domain, ".*") {
-			prefixDomains = append(prefixDomains, domain[:len(domain)-1])
-		} else {
-			exactDomains = append(exactDomains, domain)
-		}
-	}
-
-	noDomain := len(conf.Domains) == 0
-	matchDomain := func(host string) bool {
-		for i, _len := 0, len(exactDomains); i < _len; i++ {
-			if exactDomains[i] == host {
-				return true
-			}
-		}
-		for i, _len := 0, len(prefixDomains); i < _len; i++ {
-			if strings.HasPrefix(host, prefixDomains[i]) {
-				return true
-			}
-		}
-		for i, _len := 0, len(suffixDomains); i < _len; i++ {
-			if strings.HasSuffix(host, suffixDomains[i]) {
-				return true
-			}
-		}
-		return false
-	}
+	domains := newDomainMatcher(conf.Domains)
 
 	return func(next ship.Handler) ship.Handler {
 		return func(ctx *ship.Context) error {
 			if strings.Contains(ctx.GetReqHeader(ship.HeaderAcceptEncoding), "gzip") {
-				if noDomain || matchDomain(splitHost(ctx.Host())) {
+				if domains.Empty() || domains.Match(splitHost(ctx.Host())) {
 					ctx.AddRespHeader(ship.HeaderVary, ship.HeaderAcceptEncoding)
 					ctx.SetRespHeader(ship.HeaderContentEncoding, "gzip")
 
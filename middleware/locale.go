@@ -0,0 +1,37 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"github.com/xmx/ship"
+	"github.com/xmx/ship/validator"
+)
+
+// LocaleMiddleware stashes the request's Accept-Language header on its
+// context via validator.ContextWithAcceptLanguage, so that a
+// validator.StructValidator installed as ctx.Validator picks a translated
+// locale for its messages instead of always falling back to its
+// DefaultLocale. Install it ahead of any route that calls ctx.Bind.
+func LocaleMiddleware() Middleware {
+	return func(next ship.Handler) ship.Handler {
+		return func(ctx *ship.Context) error {
+			req := ctx.Request()
+			header := ctx.GetReqHeader(ship.HeaderAcceptLanguage)
+			newCtx := validator.ContextWithAcceptLanguage(req.Context(), header)
+			ctx.SetRequest(req.WithContext(newCtx))
+			return next(ctx)
+		}
+	}
+}
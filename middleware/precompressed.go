@@ -0,0 +1,60 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/xmx/ship"
+)
+
+// PrecompressedConfig configures ServeFilesPrecompressed.
+type PrecompressedConfig struct {
+	// StripPrefix is removed from the request path before it's looked up
+	// below root, mirroring http.StripPrefix.
+	//
+	// Default: ""
+	StripPrefix string
+}
+
+// ServeFilesPrecompressed returns a ship.Handler serving the files below
+// the local directory root, marking each one as a precompressed
+// candidate via ctx.SetPrecompressedCandidate. A Compress middleware
+// installed ahead of it then streams a prebuilt "<file>.gz" or
+// "<file>.br" sidecar instead of compressing the response at request
+// time, whenever one exists for the negotiated encoding.
+func ServeFilesPrecompressed(root string, config *PrecompressedConfig) ship.Handler {
+	var conf PrecompressedConfig
+	if config != nil {
+		conf = *config
+	}
+
+	fileServer := http.FileServer(http.Dir(root))
+	if conf.StripPrefix != "" {
+		fileServer = http.StripPrefix(conf.StripPrefix, fileServer)
+	}
+
+	return func(ctx *ship.Context) error {
+		req := ctx.Request()
+
+		urlPath := strings.TrimPrefix(req.URL.Path, conf.StripPrefix)
+		ctx.SetPrecompressedCandidate(filepath.Join(root, filepath.Clean("/"+urlPath)))
+
+		fileServer.ServeHTTP(ctx.ResponseWriter(), req)
+		return nil
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/xmx/ship"
+)
+
+// ErrInvalidSessionCookie is returned by a SessionCookieCodec when the
+// cookie value is malformed or fails signature/decryption verification.
+var ErrInvalidSessionCookie = errors.New("middleware: invalid session cookie")
+
+// SessionCookieCodec binds a session id to the value stored in the
+// session cookie, so that a client cannot forge or tamper with the id.
+type SessionCookieCodec interface {
+	Encode(id string) (cookieValue string, err error)
+	Decode(cookieValue string) (id string, err error)
+}
+
+// NewHMACCookieCodec returns a SessionCookieCodec that authenticates the
+// session id with HMAC-SHA256, keeping the id itself readable in the
+// cookie. secret must not be empty.
+func NewHMACCookieCodec(secret []byte) SessionCookieCodec {
+	if len(secret) == 0 {
+		panic(fmt.Errorf("middleware: empty session cookie secret"))
+	}
+	return hmacCookieCodec{secret: secret}
+}
+
+type hmacCookieCodec struct{ secret []byte }
+
+func (c hmacCookieCodec) sign(id string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c hmacCookieCodec) Encode(id string) (string, error) {
+	return id + "." + c.sign(id), nil
+}
+
+func (c hmacCookieCodec) Decode(value string) (string, error) {
+	i := len(value) - 1
+	for ; i >= 0 && value[i] != '.'; i-- {
+	}
+	if i <= 0 {
+		return "", ErrInvalidSessionCookie
+	}
+
+	id, sig := value[:i], value[i+1:]
+	if subtle.ConstantTimeCompare([]byte(sig), []byte(c.sign(id))) != 1 {
+		return "", ErrInvalidSessionCookie
+	}
+	return id, nil
+}
+
+// NewGCMCookieCodec returns a SessionCookieCodec that encrypts the
+// session id with AES-GCM, so the id itself is not visible in the
+// cookie. key must be 16, 24 or 32 bytes to select AES-128/192/256.
+func NewGCMCookieCodec(key []byte) SessionCookieCodec {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(fmt.Errorf("middleware: invalid session cookie key: %w", err))
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return gcmCookieCodec{gcm: gcm}
+}
+
+type gcmCookieCodec struct{ gcm cipher.AEAD }
+
+func (c gcmCookieCodec) Encode(id string) (string, error) {
+	nonce := make([]byte, c.gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := c.gcm.Seal(nonce, nonce, []byte(id), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (c gcmCookieCodec) Decode(value string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", ErrInvalidSessionCookie
+	}
+
+	size := c.gcm.NonceSize()
+	if len(sealed) < size {
+		return "", ErrInvalidSessionCookie
+	}
+
+	nonce, ciphertext := sealed[:size], sealed[size:]
+	id, err := c.gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidSessionCookie
+	}
+	return string(id), nil
+}
+
+// SessionConfig is used to configure the SessionMiddleware.
+type SessionConfig struct {
+	// CookieCodec binds the session id to the cookie value.
+	//
+	// Default: NewHMACCookieCodec with a random, process-local secret,
+	// which only works as long as the process doesn't restart; set it
+	// explicitly in production so that cookies survive a restart/reload.
+	CookieCodec SessionCookieCodec
+
+	// CookieName is the name of the cookie carrying the session id.
+	//
+	// Default: "session_id"
+	CookieName string
+
+	// Path, Domain and Secure are copied verbatim onto the cookie.
+	Path   string
+	Domain string
+	Secure bool
+
+	// MaxAge is both the cookie's Max-Age and the TTL passed to the
+	// Session store. Zero means a session cookie that expires with the
+	// browser and a store entry that never expires.
+	MaxAge time.Duration
+}
+
+// SessionMiddleware returns a middleware that binds a session id to a
+// signed or encrypted cookie, backed by store, and exposes it to the
+// handler chain via ctx.SessionHandle().
+func SessionMiddleware(store ship.Session, config *SessionConfig) Middleware {
+	var conf SessionConfig
+	if config != nil {
+		conf = *config
+	}
+	if conf.CookieCodec == nil {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+		conf.CookieCodec = NewHMACCookieCodec(secret)
+	}
+	if conf.CookieName == "" {
+		conf.CookieName = "session_id"
+	}
+
+	writeCookie := func(w http.ResponseWriter, id string) error {
+		value, err := conf.CookieCodec.Encode(id)
+		if err != nil {
+			return err
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     conf.CookieName,
+			Value:    value,
+			Path:     conf.Path,
+			Domain:   conf.Domain,
+			Secure:   conf.Secure,
+			HttpOnly: true,
+			MaxAge:   int(conf.MaxAge / time.Second),
+			SameSite: http.SameSiteLaxMode,
+		})
+		return nil
+	}
+
+	return func(next ship.Handler) ship.Handler {
+		return func(ctx *ship.Context) error {
+			req := ctx.Request()
+			resp := ctx.ResponseWriter()
+
+			var id string
+			if cookie, err := req.Cookie(conf.CookieName); err == nil {
+				if decoded, err := conf.CookieCodec.Decode(cookie.Value); err == nil {
+					id = decoded
+				}
+			}
+
+			isNew := id == ""
+			if isNew {
+				newID, err := ship.NewSessionID()
+				if err != nil {
+					return err
+				}
+				id = newID
+			}
+
+			handle := &ship.SessionHandle{Store: store, ID: id, TTL: conf.MaxAge}
+			handle.SetRegenerateHook(func(_ context.Context, newID string) error {
+				return writeCookie(resp, newID)
+			})
+			ctx.SetRequest(ship.WithSession(req, handle))
+
+			if isNew {
+				if err := writeCookie(resp, id); err != nil {
+					return err
+				}
+			}
+
+			return next(ctx)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"strings"
+	"testing"
+)
+
+func testCodecs() map[string]SessionCookieCodec {
+	return map[string]SessionCookieCodec{
+		"hmac": NewHMACCookieCodec([]byte("hmac-test-secret")),
+		"gcm":  NewGCMCookieCodec([]byte("0123456789abcdef")), // 16 bytes: AES-128
+	}
+}
+
+func TestCookieCodecRoundTrip(t *testing.T) {
+	for name, codec := range testCodecs() {
+		t.Run(name, func(t *testing.T) {
+			const id = "session-id-1234"
+
+			value, err := codec.Encode(id)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			got, err := codec.Decode(value)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if got != id {
+				t.Fatalf("Decode returned %q, want %q", got, id)
+			}
+		})
+	}
+}
+
+func TestCookieCodecRejectsTampering(t *testing.T) {
+	for name, codec := range testCodecs() {
+		t.Run(name, func(t *testing.T) {
+			value, err := codec.Encode("session-id-1234")
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			tampered := flipLastByte(t, value)
+			if _, err := codec.Decode(tampered); err != ErrInvalidSessionCookie {
+				t.Fatalf("Decode(tampered) = %v, want ErrInvalidSessionCookie", err)
+			}
+		})
+	}
+}
+
+func TestCookieCodecRejectsForgedID(t *testing.T) {
+	// An attacker who doesn't know the secret shouldn't be able to swap in
+	// a different session id, even by reusing a genuine signature/nonce
+	// from another value they were issued.
+	for name, codec := range testCodecs() {
+		t.Run(name, func(t *testing.T) {
+			victim, err := codec.Encode("victim-id")
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			forged := strings.Replace(victim, "victim-id", "attacker-id", 1)
+			if forged == victim {
+				// gcmCookieCodec's output doesn't contain the plaintext
+				// id, so there's nothing to substring-replace; Decode
+				// on the untouched value must still yield the original.
+				id, err := codec.Decode(forged)
+				if err != nil || id != "victim-id" {
+					t.Fatalf("Decode(victim) = (%q, %v), want (\"victim-id\", nil)", id, err)
+				}
+				return
+			}
+
+			if _, err := codec.Decode(forged); err != ErrInvalidSessionCookie {
+				t.Fatalf("Decode(forged) = %v, want ErrInvalidSessionCookie", err)
+			}
+		})
+	}
+}
+
+func flipLastByte(t *testing.T, s string) string {
+	t.Helper()
+	if s == "" {
+		t.Fatal("cannot tamper with an empty value")
+	}
+	b := []byte(s)
+	b[len(b)-1] ^= 0x01
+	return string(b)
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ship
+
+import "context"
+
+type precompressCandidateKey struct{}
+
+// SetPrecompressedCandidate marks path as the on-disk file a static file
+// server is about to serve, so that a compression middleware installed
+// ahead of it can look for a prebuilt "path.gz" or "path.br" sidecar and
+// stream it directly instead of compressing the response at request
+// time.
+func (c *Context) SetPrecompressedCandidate(path string) {
+	ctx := context.WithValue(c.Request().Context(), precompressCandidateKey{}, path)
+	c.SetRequest(c.Request().WithContext(ctx))
+}
+
+// PrecompressedCandidate returns the path set by SetPrecompressedCandidate
+// for the current request, if any.
+func (c *Context) PrecompressedCandidate() (path string, ok bool) {
+	path, ok = c.Request().Context().Value(precompressCandidateKey{}).(string)
+	return
+}
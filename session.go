@@ -17,38 +17,132 @@ package ship
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 // Session represents an interface about the session.
 type Session interface {
 	// If the session id does not exist, it should return (nil, nil).
 	GetSession(ctx context.Context, id string) (value interface{}, err error)
+
+	// SetSession is equivalent to SetSessionWithTTL with the store's
+	// default ttl, such as SessionOptions.TTL for NewMemorySession.
 	SetSession(ctx context.Context, id string, value interface{}) error
+
+	// SetSessionWithTTL sets the session value and schedules it to expire
+	// after ttl. A zero ttl means the session never expires.
+	SetSessionWithTTL(ctx context.Context, id string, value interface{}, ttl time.Duration) error
+
 	DelSession(ctx context.Context, id string) error
 }
 
-// NewMemorySession return a Session implementation based on the memory.
-func NewMemorySession() Session {
-	return memorySession{store: new(sync.Map)}
+// SessionOptions configures the Session backends provided by this package.
+type SessionOptions struct {
+	// TTL is the default expiration duration used by SetSession.
+	//
+	// Default: 0 (never expires)
+	TTL time.Duration
+
+	// ReapInterval is how often NewMemorySession scans for and removes
+	// expired sessions in the background.
+	//
+	// Default: time.Minute
+	ReapInterval time.Duration
+}
+
+// NewMemorySession returns a Session implementation based on the memory,
+// with optional per-entry TTL enforced by a background reaper goroutine.
+//
+// The returned Session also implements io.Closer. Close stops the reaper
+// goroutine and should be called when the Session is no longer needed.
+func NewMemorySession(opts *SessionOptions) Session {
+	var options SessionOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.ReapInterval <= 0 {
+		options.ReapInterval = time.Minute
+	}
+
+	m := &memorySession{
+		store: new(sync.Map),
+		ttl:   options.TTL,
+		done:  make(chan struct{}),
+	}
+	go m.reap(options.ReapInterval)
+	return m
+}
+
+type memoryEntry struct {
+	value    interface{}
+	expireAt time.Time // zero means no expiration
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
 }
 
 type memorySession struct {
 	store *sync.Map
+	ttl   time.Duration
+	done  chan struct{}
+	once  sync.Once
 }
 
-func (m memorySession) GetSession(_ context.Context, id string) (value interface{}, err error) {
-	if val, ok := m.store.Load(id); ok {
-		return val, nil
+func (m *memorySession) GetSession(_ context.Context, id string) (value interface{}, err error) {
+	v, ok := m.store.Load(id)
+	if !ok {
+		return nil, nil
+	}
+
+	entry := v.(memoryEntry)
+	if entry.expired(time.Now()) {
+		m.store.Delete(id)
+		return nil, nil
 	}
-	return
+	return entry.value, nil
 }
 
-func (m memorySession) SetSession(_ context.Context, id string, value interface{}) error {
-	m.store.Store(id, value)
+func (m *memorySession) SetSession(ctx context.Context, id string, value interface{}) error {
+	return m.SetSessionWithTTL(ctx, id, value, m.ttl)
+}
+
+func (m *memorySession) SetSessionWithTTL(_ context.Context, id string, value interface{}, ttl time.Duration) error {
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expireAt = time.Now().Add(ttl)
+	}
+	m.store.Store(id, entry)
 	return nil
 }
 
-func (m memorySession) DelSession(_ context.Context, id string) error {
+func (m *memorySession) DelSession(_ context.Context, id string) error {
 	m.store.Delete(id)
 	return nil
 }
+
+func (m *memorySession) reap(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			m.store.Range(func(key, v interface{}) bool {
+				if v.(memoryEntry).expired(now) {
+					m.store.Delete(key)
+				}
+				return true
+			})
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// Close stops the background reaper goroutine.
+func (m *memorySession) Close() error {
+	m.once.Do(func() { close(m.done) })
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package session provides ship.Session backends that store the session
+// values outside of the current process, such as Redis, Memcached and
+// the local filesystem.
+package session
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"time"
+)
+
+// Codec serializes a session value to bytes and back, which the backends
+// in this package need because they store the session across a process,
+// network or file boundary instead of keeping the Go value in memory.
+type Codec interface {
+	Encode(value interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// GobCodec is a Codec based on encoding/gob. Any concrete type stored in
+// a session value must be registered with gob.Register beforehand, since
+// that's how Decode is able to reconstruct it as an interface{}.
+//
+// It's the default Codec used by the backends in this package.
+var GobCodec Codec = gobCodec{}
+
+type gobCodec struct{}
+
+func (gobCodec) Encode(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Decode(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// JSONCodec is a Codec based on encoding/json. Since JSON carries no type
+// information, Decode always returns the generic types produced by
+// json.Unmarshal (map[string]interface{}, []interface{}, float64, etc.)
+// instead of the original concrete type.
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(value interface{}) ([]byte, error) { return json.Marshal(value) }
+
+func (jsonCodec) Decode(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Options configures a Session backend provided by this package.
+type Options struct {
+	// Codec serializes the session values. Default: GobCodec.
+	Codec Codec
+
+	// TTL is the default expiration applied by SetSession.
+	//
+	// Default: 0, which means the Redis/Memcached entry never expires
+	// and the File entry is never removed by the backend itself.
+	TTL time.Duration
+
+	// KeyPrefix is prepended to every session id, so that the sessions
+	// stored by this package can share a Redis/Memcached instance with
+	// other, unrelated data without colliding.
+	//
+	// Default: ""
+	KeyPrefix string
+}
+
+func (o *Options) withDefaults() Options {
+	var opts Options
+	if o != nil {
+		opts = *o
+	}
+	if opts.Codec == nil {
+		opts.Codec = GobCodec
+	}
+	return opts
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/xmx/ship"
+)
+
+// NewFileSession returns a ship.Session backend storing every session as
+// a file below dir. dir is created, along with any missing parents, if
+// it does not already exist.
+func NewFileSession(dir string, opts *Options) (ship.Session, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &fileSession{dir: dir, opts: opts.withDefaults()}, nil
+}
+
+type fileSession struct {
+	dir  string
+	opts Options
+}
+
+// fileEnvelope is the on-disk wrapper around a Codec-encoded value,
+// carrying the expiration so that GetSession can reject a stale file
+// without a separate background reaper.
+type fileEnvelope struct {
+	ExpireAt int64 // UnixNano; zero means never expires
+	Data     []byte
+}
+
+// path derives the file path for id. The id is hashed rather than used
+// verbatim so that it can never be read as a relative or absolute path
+// escaping dir.
+func (s *fileSession) path(id string) string {
+	sum := sha256.Sum256([]byte(s.opts.KeyPrefix + id))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+func (s *fileSession) GetSession(_ context.Context, id string) (interface{}, error) {
+	data, err := os.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var envelope fileEnvelope
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&envelope); err != nil {
+		return nil, err
+	}
+	if envelope.ExpireAt != 0 && time.Now().UnixNano() >= envelope.ExpireAt {
+		os.Remove(s.path(id))
+		return nil, nil
+	}
+
+	return s.opts.Codec.Decode(envelope.Data)
+}
+
+func (s *fileSession) SetSession(ctx context.Context, id string, value interface{}) error {
+	return s.SetSessionWithTTL(ctx, id, value, s.opts.TTL)
+}
+
+func (s *fileSession) SetSessionWithTTL(_ context.Context, id string, value interface{}, ttl time.Duration) error {
+	data, err := s.opts.Codec.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	envelope := fileEnvelope{Data: data}
+	if ttl > 0 {
+		envelope.ExpireAt = time.Now().Add(ttl).UnixNano()
+	}
+
+	var buf bytes.Buffer
+	if err = gob.NewEncoder(&buf).Encode(&envelope); err != nil {
+		return err
+	}
+
+	path := s.path(id)
+	tmp := path + fmt.Sprintf(".%d.tmp", os.Getpid())
+	if err = os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *fileSession) DelSession(_ context.Context, id string) error {
+	err := os.Remove(s.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
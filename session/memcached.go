@@ -0,0 +1,79 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/xmx/ship"
+)
+
+// NewMemcachedSession returns a ship.Session backend storing the sessions
+// in Memcached, expiring them with the native Memcached TTL.
+func NewMemcachedSession(client *memcache.Client, opts *Options) ship.Session {
+	return &memcachedSession{client: client, opts: opts.withDefaults()}
+}
+
+type memcachedSession struct {
+	client *memcache.Client
+	opts   Options
+}
+
+func (s *memcachedSession) key(id string) string { return s.opts.KeyPrefix + id }
+
+func (s *memcachedSession) GetSession(_ context.Context, id string) (interface{}, error) {
+	item, err := s.client.Get(s.key(id))
+	if err == memcache.ErrCacheMiss {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return s.opts.Codec.Decode(item.Value)
+}
+
+func (s *memcachedSession) SetSession(ctx context.Context, id string, value interface{}) error {
+	return s.SetSessionWithTTL(ctx, id, value, s.opts.TTL)
+}
+
+func (s *memcachedSession) SetSessionWithTTL(_ context.Context, id string, value interface{}, ttl time.Duration) error {
+	data, err := s.opts.Codec.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	expiration := int32(ttl / time.Second)
+	if ttl > 0 && expiration == 0 {
+		expiration = 1
+	} else if ttl > 30*24*time.Hour {
+		// Memcached reads an expiration over 30 days as a Unix timestamp.
+		expiration = int32(time.Now().Add(ttl).Unix())
+	}
+
+	return s.client.Set(&memcache.Item{
+		Key:        s.key(id),
+		Value:      data,
+		Expiration: expiration,
+	})
+}
+
+func (s *memcachedSession) DelSession(_ context.Context, id string) error {
+	err := s.client.Delete(s.key(id))
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+	return err
+}
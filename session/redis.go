@@ -0,0 +1,62 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/xmx/ship"
+)
+
+// NewRedisSession returns a ship.Session backend storing the sessions in
+// Redis, expiring them with the native Redis TTL.
+func NewRedisSession(client redis.Cmdable, opts *Options) ship.Session {
+	return &redisSession{client: client, opts: opts.withDefaults()}
+}
+
+type redisSession struct {
+	client redis.Cmdable
+	opts   Options
+}
+
+func (s *redisSession) key(id string) string { return s.opts.KeyPrefix + id }
+
+func (s *redisSession) GetSession(ctx context.Context, id string) (interface{}, error) {
+	data, err := s.client.Get(ctx, s.key(id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return s.opts.Codec.Decode(data)
+}
+
+func (s *redisSession) SetSession(ctx context.Context, id string, value interface{}) error {
+	return s.SetSessionWithTTL(ctx, id, value, s.opts.TTL)
+}
+
+func (s *redisSession) SetSessionWithTTL(ctx context.Context, id string, value interface{}, ttl time.Duration) error {
+	data, err := s.opts.Codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.key(id), data, ttl).Err()
+}
+
+func (s *redisSession) DelSession(ctx context.Context, id string) error {
+	return s.client.Del(ctx, s.key(id)).Err()
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ship
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// NewSessionID returns a new, cryptographically random session id, hex
+// encoded so that it's safe to use as a cookie value and a map/file key.
+func NewSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type sessionContextKey struct{}
+
+// SessionHandle binds the Session store used by middleware.SessionMiddleware
+// to the id of the current request's session, and offers convenience
+// wrappers around the Session methods, including id regeneration.
+type SessionHandle struct {
+	Store Session
+	ID    string
+
+	// TTL is passed as-is to Store.SetSessionWithTTL by Set and
+	// Regenerate. It's set by middleware.SessionMiddleware from
+	// SessionConfig.MaxAge, so that the store entry expires together with
+	// the cookie. Zero means the entry never expires.
+	TTL time.Duration
+
+	// regenerate is set by middleware.SessionMiddleware to rewrite the
+	// response cookie when Regenerate is called.
+	regenerate func(ctx context.Context, newID string) error
+}
+
+// SetRegenerateHook installs the function called by Regenerate to persist
+// the new id outside of the Session store, such as rewriting the response
+// cookie. It's called by middleware.SessionMiddleware.
+func (h *SessionHandle) SetRegenerateHook(f func(ctx context.Context, newID string) error) {
+	h.regenerate = f
+}
+
+// Get is a shortcut for h.Store.GetSession(ctx, h.ID).
+func (h *SessionHandle) Get(ctx context.Context) (interface{}, error) {
+	return h.Store.GetSession(ctx, h.ID)
+}
+
+// Set is a shortcut for h.Store.SetSessionWithTTL(ctx, h.ID, value, h.TTL).
+func (h *SessionHandle) Set(ctx context.Context, value interface{}) error {
+	return h.Store.SetSessionWithTTL(ctx, h.ID, value, h.TTL)
+}
+
+// Regenerate moves value to a freshly generated session id, deletes the
+// old one and updates the response cookie. Call it after a privilege
+// change, such as a successful login, to prevent session fixation.
+func (h *SessionHandle) Regenerate(ctx context.Context, value interface{}) error {
+	newID, err := NewSessionID()
+	if err != nil {
+		return err
+	}
+	if err = h.Store.SetSessionWithTTL(ctx, newID, value, h.TTL); err != nil {
+		return err
+	}
+	if h.regenerate != nil {
+		if err = h.regenerate(ctx, newID); err != nil {
+			return err
+		}
+	}
+
+	oldID := h.ID
+	h.ID = newID
+	return h.Store.DelSession(ctx, oldID)
+}
+
+// SessionHandle returns the SessionHandle bound to the current request by
+// middleware.SessionMiddleware, or nil if it was not used.
+//
+// Named SessionHandle, not Session, because Context already has a Session
+// Session field backing GetSession/SetSession/DelSession.
+func (c *Context) SessionHandle() *SessionHandle {
+	h, _ := c.Request().Context().Value(sessionContextKey{}).(*SessionHandle)
+	return h
+}
+
+// WithSession attaches handle to r's Context so that Context.SessionHandle
+// can retrieve it later in the handler chain. It's used by
+// middleware.SessionMiddleware together with Context.SetRequest.
+func WithSession(r *http.Request, handle *SessionHandle) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, handle))
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ship
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/xmx/ship/utils"
+)
+
+// ShutdownOptions configures RunAndWait.
+type ShutdownOptions struct {
+	// Signals are the OS signals that trigger a graceful shutdown.
+	//
+	// Default: SIGINT, SIGTERM, SIGHUP
+	Signals []os.Signal
+
+	// Timeout bounds how long s.Shutdown and Manager's hooks are given to
+	// finish once a signal arrives.
+	//
+	// Default: 30 * time.Second
+	Timeout time.Duration
+
+	// Manager runs the user's cleanup hooks after s.Shutdown finishes
+	// draining in-flight requests.
+	//
+	// Default: utils.DefaultManager()
+	Manager *utils.ShutdownManager
+}
+
+// RunAndWait blocks until one of opts.Signals is received, then calls
+// s.Shutdown to drain the in-flight requests, followed by opts.Manager's
+// hooks, both bounded by opts.Timeout. It's the one-liner a server's main
+// function needs to get correct in-flight-request draining and resource
+// cleanup, without hand-rolling the signal plumbing.
+func RunAndWait(s *Ship, opts *ShutdownOptions) error {
+	var options ShutdownOptions
+	if opts != nil {
+		options = *opts
+	}
+	if len(options.Signals) == 0 {
+		options.Signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 30 * time.Second
+	}
+	if options.Manager == nil {
+		options.Manager = utils.DefaultManager()
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, options.Signals...)
+	<-ch
+	signal.Stop(ch)
+
+	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout)
+	defer cancel()
+
+	shutdownErr := s.Shutdown(ctx)
+	hookErr := options.Manager.Shutdown(ctx)
+	return errors.Join(shutdownErr, hookErr)
+}
@@ -1,26 +1,137 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"sort"
+	"sync"
 )
 
-var funcs = make([]func(), 0)
+// ExitHook is a function registered with a ShutdownManager to run during
+// shutdown. It receives the context passed to Shutdown, so that a slow
+// cleanup step can respect its deadline.
+type ExitHook func(ctx context.Context) error
 
-// OnExit registers a exit function.
-func OnExit(f func()) {
-	funcs = append(funcs, f)
+type namedHook struct {
+	name     string
+	priority int
+	hook     ExitHook
+
+	// legacy marks a hook registered through the package-level OnExit, so
+	// that reverseWithinPriority can keep it FIFO regardless of priority,
+	// without bending the ordering contract of Register itself. See
+	// ShutdownManager.
+	legacy bool
+}
+
+// ShutdownManager collects the cleanup hooks a program should run when it
+// exits. Hooks registered with Register run in descending priority order
+// and, within the same priority, in LIFO registration order, the same
+// order a chain of defers in a single function would run.
+//
+// The package-level OnExit is the one exception: for backward
+// compatibility with its pre-ShutdownManager behavior, hooks it registers
+// always run in plain FIFO order relative to each other, regardless of
+// where priority 0 places them among hooks registered directly with
+// Register.
+//
+// A ShutdownManager is safe for concurrent use.
+type ShutdownManager struct {
+	mu    sync.Mutex
+	hooks []namedHook
+}
+
+// NewShutdownManager returns an empty ShutdownManager.
+func NewShutdownManager() *ShutdownManager { return new(ShutdownManager) }
+
+// Register adds a named hook at the given priority. name is only used to
+// annotate the error Shutdown returns if the hook fails.
+func (m *ShutdownManager) Register(name string, priority int, hook ExitHook) {
+	m.register(name, priority, false, hook)
 }
 
-// CallOnExit calls the exit functions.
-func CallOnExit() {
-	for _, f := range funcs {
-		f()
+func (m *ShutdownManager) register(name string, priority int, legacy bool, hook ExitHook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, namedHook{name: name, priority: priority, legacy: legacy, hook: hook})
+}
+
+// Shutdown runs every registered hook, even if an earlier one fails or
+// ctx expires, so that a single stuck resource cannot prevent the rest
+// from being released. The returned error, if any, wraps every failing
+// hook's error and can be inspected with errors.Is/errors.As.
+func (m *ShutdownManager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := make([]namedHook, len(m.hooks))
+	copy(hooks, m.hooks)
+	m.mu.Unlock()
+
+	sort.SliceStable(hooks, func(i, j int) bool { return hooks[i].priority > hooks[j].priority })
+	reverseWithinPriority(hooks)
+
+	var errs []error
+	for _, h := range hooks {
+		if err := h.hook(ctx); err != nil {
+			if h.name == "" {
+				errs = append(errs, err)
+			} else {
+				errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			}
+		}
 	}
+	return errors.Join(errs...)
 }
 
+// reverseWithinPriority reverses each contiguous run of equal-priority
+// hooks in place, turning the stable registration order within a tier
+// into LIFO order, without disturbing the order between tiers or the
+// relative order of the legacy hooks within a tier; see ShutdownManager.
+func reverseWithinPriority(hooks []namedHook) {
+	start := 0
+	for i := 1; i <= len(hooks); i++ {
+		if i == len(hooks) || hooks[i].priority != hooks[start].priority {
+			reverseNonLegacy(hooks[start:i])
+			start = i
+		}
+	}
+}
+
+// reverseNonLegacy reverses the relative order of tier's non-legacy hooks
+// in place, leaving each legacy hook at its original position.
+func reverseNonLegacy(tier []namedHook) {
+	var idx []int
+	for i, h := range tier {
+		if !h.legacy {
+			idx = append(idx, i)
+		}
+	}
+	for l, r := 0, len(idx)-1; l < r; l, r = l+1, r-1 {
+		tier[idx[l]], tier[idx[r]] = tier[idx[r]], tier[idx[l]]
+	}
+}
+
+var defaultManager = NewShutdownManager()
+
+// DefaultManager returns the package-level ShutdownManager backing
+// OnExit, CallOnExit and Exit.
+func DefaultManager() *ShutdownManager { return defaultManager }
+
+// OnExit registers a exit function with the DefaultManager at priority 0,
+// running in FIFO order relative to other OnExit hooks; see
+// ShutdownManager.
+func OnExit(f func()) {
+	defaultManager.register("", 0, true, func(context.Context) error { f(); return nil })
+}
+
+// CallOnExit calls the exit functions registered with the DefaultManager,
+// using context.Background so that none of them is time-bounded.
+func CallOnExit() { _ = defaultManager.Shutdown(context.Background()) }
+
 // Exit exits the process with the code, but calling the exit functions
 // before exiting.
 func Exit(code int) {
 	CallOnExit()
 	os.Exit(code)
-}
\ No newline at end of file
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ship
+
+import (
+	"net/http"
+	"strings"
+)
+
+// FieldError describes why a single struct field failed validation.
+type FieldError struct {
+	Field   string // e.g. "Email"
+	Tag     string // e.g. "email"
+	Param   string // e.g. "3" for the rule "min=3"
+	Message string // human-readable, translated message
+}
+
+// ValidationErrors is returned by a struct-tag Validator adapter, such as
+// the one in the validator subpackage, when the data fails one or more
+// rules. The default error handler renders it as the JSON body
+// {"errors": [...]}  with the status code from StatusCode.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 0 {
+		return "validation failed"
+	}
+
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// StatusCode reports the HTTP status the default error handler should use
+// to render this error, HTTP 422 Unprocessable Entity.
+func (e ValidationErrors) StatusCode() int { return http.StatusUnprocessableEntity }
@@ -0,0 +1,116 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	govalidator "github.com/go-playground/validator/v10"
+)
+
+var (
+	localesMu sync.RWMutex
+	locales   = map[string]map[string]string{
+		"en": englishTemplates,
+		"zh": chineseTemplates,
+	}
+)
+
+var englishTemplates = map[string]string{
+	"required": "{field} is required",
+	"email":    "{field} must be a valid email address",
+	"min":      "{field} must be at least {param} characters",
+	"max":      "{field} must be at most {param} characters",
+}
+
+var chineseTemplates = map[string]string{
+	"required": "{field} 不能为空",
+	"email":    "{field} 必须是合法的邮箱地址",
+	"min":      "{field} 长度不能小于 {param}",
+	"max":      "{field} 长度不能大于 {param}",
+}
+
+// RegisterLocale registers, or replaces, the message templates used for
+// lang. Each template may reference {field}, {tag} and {param}, which are
+// substituted with the failing field's name, validation tag and param.
+func RegisterLocale(lang string, templates map[string]string) {
+	localesMu.Lock()
+	defer localesMu.Unlock()
+	locales[lang] = templates
+}
+
+func translate(locale string, fe govalidator.FieldError) string {
+	localesMu.RLock()
+	templates, ok := locales[locale]
+	if !ok {
+		templates = locales["en"]
+	}
+	tmpl, ok := templates[fe.Tag()]
+	localesMu.RUnlock()
+	if !ok {
+		tmpl = "{field} failed validation on the '{tag}' rule"
+	}
+
+	replacer := strings.NewReplacer(
+		"{field}", fe.Field(),
+		"{tag}", fe.Tag(),
+		"{param}", fe.Param(),
+	)
+	return replacer.Replace(tmpl)
+}
+
+type acceptLanguageKey struct{}
+
+// ContextWithAcceptLanguage returns a context carrying the raw
+// Accept-Language header value, so that a StructValidator can pick a
+// registered locale for its translated messages.
+// middleware.LocaleMiddleware calls this before the request reaches
+// ctx.Bind, which is what actually invokes the Validator.
+func ContextWithAcceptLanguage(ctx context.Context, header string) context.Context {
+	return context.WithValue(ctx, acceptLanguageKey{}, header)
+}
+
+// localeFromContext walks the Accept-Language value stashed by
+// ContextWithAcceptLanguage in q-value order and returns the first
+// registered locale, or def if none matches.
+func localeFromContext(ctx context.Context, def string) string {
+	header, _ := ctx.Value(acceptLanguageKey{}).(string)
+	best, bestQ := def, 0.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.SplitN(part, ";", 2)
+		lang := strings.TrimSpace(fields[0])
+		lang = strings.SplitN(lang, "-", 2)[0]
+		q := 1.0
+		if len(fields) == 2 {
+			if v, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(fields[1]), "q="), 64); err == nil {
+				q = v
+			}
+		}
+		if lang == "" || q <= bestQ {
+			continue
+		}
+
+		localesMu.RLock()
+		_, ok := locales[lang]
+		localesMu.RUnlock()
+		if ok {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 xgfone
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validator adapts github.com/go-playground/validator/v10 to the
+// ship.Validator interface, translating its FieldErrors into
+// ship.ValidationErrors with locale-aware messages.
+package validator
+
+import (
+	"context"
+
+	govalidator "github.com/go-playground/validator/v10"
+	"github.com/xmx/ship"
+)
+
+// FieldLevel is an alias of go-playground/validator's FieldLevel, so that
+// a caller of RegisterValidation doesn't need to import that package too.
+type FieldLevel = govalidator.FieldLevel
+
+// Options configures NewStructValidator.
+type Options struct {
+	// DefaultLocale is used when the request carries no Accept-Language
+	// header, or none of its locales has been registered.
+	//
+	// Default: "en"
+	DefaultLocale string
+}
+
+// StructValidator is a ship.Validator adapter that validates a struct
+// using the tags registered on its fields, such as
+// `validate:"required,email,min=3"`.
+type StructValidator struct {
+	validate *govalidator.Validate
+	locale   string
+}
+
+// NewStructValidator returns a new StructValidator.
+func NewStructValidator(opts *Options) *StructValidator {
+	var options Options
+	if opts != nil {
+		options = *opts
+	}
+	if options.DefaultLocale == "" {
+		options.DefaultLocale = "en"
+	}
+
+	return &StructValidator{validate: govalidator.New(), locale: options.DefaultLocale}
+}
+
+// Validate implements the ship.Validator interface. On failure it returns
+// a ship.ValidationErrors, translated using the locale carried by ctx
+// (see ContextWithAcceptLanguage), instead of the raw
+// govalidator.ValidationErrors.
+func (v *StructValidator) Validate(ctx context.Context, data interface{}) error {
+	err := v.validate.Struct(data)
+	if err == nil {
+		return nil
+	}
+
+	fieldErrs, ok := err.(govalidator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	locale := localeFromContext(ctx, v.locale)
+	errs := make(ship.ValidationErrors, len(fieldErrs))
+	for i, fe := range fieldErrs {
+		errs[i] = ship.FieldError{
+			Field:   fe.Field(),
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Message: translate(locale, fe),
+		}
+	}
+	return errs
+}
+
+// RegisterValidation registers a custom validation rule under tag, in
+// addition to the built-in ones such as "required" or "email".
+func (v *StructValidator) RegisterValidation(tag string, fn func(FieldLevel) bool) error {
+	return v.validate.RegisterValidation(tag, func(fl govalidator.FieldLevel) bool { return fn(fl) })
+}